@@ -1,45 +1,311 @@
-package main
-
-import "sync"
-
-var appBus *Bus
-
-// // Event struct can hold any bus event
-// type Event struct {
-// 	Type string
-// 	Data interface{}
-// }
-
-type Event struct {
-    Type      string      `json:"type"`
-    Data      interface{} `json:"data"`
-    Timestamp int64       `json:"timestamp"`
-}
-
-type Bus struct {
-	subscribers []chan Event
-	lock        sync.Mutex
-}
-
-func NewBus() *Bus {
-	return &Bus{}
-}
-
-func (b *Bus) Subscribe() <-chan Event {
-	b.lock.Lock()
-	defer b.lock.Unlock()
-	ch := make(chan Event, 20) // buffered channel
-	b.subscribers = append(b.subscribers, ch)
-	return ch
-}
-
-func (b *Bus) Publish(event Event) {
-	b.lock.Lock()
-	defer b.lock.Unlock()
-	for _, ch := range b.subscribers {
-		select {
-		case ch <- event:
-		default: // avoid blocking
-		}
-	}
-}
\ No newline at end of file
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var appBus *Bus
+
+var errNoTransport = errors.New("bus: no transport configured")
+
+type Event struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	Timestamp int64       `json:"timestamp"`
+	ID        string      `json:"id,omitempty"`
+}
+
+// ConnState describes the current health of the backend connection so the
+// TUI can surface it to the user instead of just freezing mid-stream.
+type ConnState int
+
+const (
+	ConnConnected ConnState = iota
+	ConnReconnecting
+	ConnOffline
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnConnected:
+		return "connected"
+	case ConnReconnecting:
+		return "reconnecting"
+	default:
+		return "offline"
+	}
+}
+
+// OverflowPolicy controls what a Subscription does when its buffered
+// channel is full and a new Event is published.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the incoming event, leaving the queue as-is.
+	// This is the default and matches the Bus's original behaviour.
+	DropNewest OverflowPolicy = iota
+	// Block never drops: events wait in an unbounded per-subscription
+	// queue that a dedicated goroutine feeds into the channel, so Publish
+	// does not wait on a slow consumer. Use for consumers that must not
+	// miss anything, e.g. the SQLite history logger.
+	Block
+	// DropOldest evicts the oldest queued event to make room for the new
+	// one.
+	DropOldest
+	// Coalesce merges consecutive chat.char events into one instead of
+	// dropping either, so streamed text never loses glyphs under load.
+	Coalesce
+)
+
+const defaultSubBuffer = 20
+
+// SubOptions configures a single subscription's buffering and overflow
+// behaviour.
+type SubOptions struct {
+	Policy     OverflowPolicy
+	BufferSize int
+}
+
+// Subscription is a live view onto a topic pattern. Unsubscribe stops
+// delivery and Dropped reports how many events the overflow policy has
+// discarded so far.
+type Subscription struct {
+	bus     *Bus
+	pattern string
+	opts    SubOptions
+	ch      chan Event
+	dropped uint64
+
+	mu      sync.Mutex
+	closed  bool   // set by closeDown; deliver checks it under the same lock
+	pending *Event // held back by Coalesce, retried on the next delivery
+
+	// blockQueue/blockCond back the Block policy: deliver only appends to
+	// the queue and signals, it never itself blocks on s.ch, so one slow
+	// Block consumer can't stall Publish (and every other subscriber)
+	// behind it. pumpBlock is the sole goroutine that drains the queue
+	// into s.ch, blocking there instead.
+	blockQueue []Event
+	blockCond  *sync.Cond
+	pumpDone   chan struct{}
+}
+
+func (s *Subscription) C() <-chan Event { return s.ch }
+
+func (s *Subscription) Dropped() uint64 { return atomic.LoadUint64(&s.dropped) }
+
+func (s *Subscription) Unsubscribe() { s.bus.unsubscribe(s) }
+
+// pumpBlock drains blockQueue into s.ch for a Block subscription, blocking
+// on the send outside s.mu so deliver (and therefore Publish) never waits
+// on a slow consumer. It exits once closed and the queue has drained.
+func (s *Subscription) pumpBlock() {
+	s.mu.Lock()
+	for {
+		for len(s.blockQueue) == 0 && !s.closed {
+			s.blockCond.Wait()
+		}
+		if len(s.blockQueue) == 0 {
+			s.mu.Unlock()
+			close(s.pumpDone)
+			return
+		}
+		evt := s.blockQueue[0]
+		s.blockQueue = s.blockQueue[1:]
+		s.mu.Unlock()
+		s.ch <- evt
+		s.mu.Lock()
+	}
+}
+
+// closeDown marks the subscription closed and closes s.ch once any
+// in-flight delivery is done. For Block subscriptions that means waiting
+// for pumpBlock to finish draining and exit first, so closing s.ch here
+// can never race a send still in flight inside pumpBlock.
+func (s *Subscription) closeDown() {
+	s.mu.Lock()
+	s.closed = true
+	s.blockCond.Broadcast()
+	s.mu.Unlock()
+
+	if s.opts.Policy == Block {
+		<-s.pumpDone
+	}
+	close(s.ch)
+}
+
+// deliver applies the subscription's overflow policy to one event. Other
+// than Block (queued, see blockQueue), it holds s.mu for the whole call,
+// including any non-blocking send, so it can never race closeDown's
+// close(s.ch) and panic on a closed channel.
+func (s *Subscription) deliver(evt Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	switch s.opts.Policy {
+	case Block:
+		s.blockQueue = append(s.blockQueue, evt)
+		s.blockCond.Signal()
+
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- evt:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+				return
+			}
+		}
+
+	case Coalesce:
+		if s.pending != nil {
+			select {
+			case s.ch <- *s.pending:
+				s.pending = nil
+			default:
+			}
+		}
+		if s.pending != nil && evt.Type == "chat.char" && s.pending.Type == "chat.char" {
+			if prev, ok := s.pending.Data.(string); ok {
+				if next, ok := evt.Data.(string); ok {
+					s.pending.Data = prev + next
+					return
+				}
+			}
+		}
+		select {
+		case s.ch <- evt:
+		default:
+			if evt.Type == "chat.char" {
+				e := evt
+				s.pending = &e
+			} else {
+				atomic.AddUint64(&s.dropped, 1)
+			}
+		}
+
+	default: // DropNewest
+		select {
+		case s.ch <- evt:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// matchTopic reports whether eventType falls under pattern. "chat.*",
+// "tool.*" and "sys.*" match by prefix; "*" (or "") matches everything;
+// anything else must match exactly.
+func matchTopic(pattern, eventType string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, ".*"); ok {
+		return strings.HasPrefix(eventType, prefix+".")
+	}
+	return pattern == eventType
+}
+
+type Bus struct {
+	subscribers []*Subscription
+	lock        sync.Mutex
+
+	transport ChatTransport
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe opens a subscription to every event whose Type matches pattern
+// ("chat.*", "tool.*", "sys.*", an exact type, or "*"/"" for everything),
+// buffered and overflow-managed per opts.
+func (b *Bus) Subscribe(pattern string, opts SubOptions) *Subscription {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultSubBuffer
+	}
+	sub := &Subscription{
+		bus:     b,
+		pattern: pattern,
+		opts:    opts,
+		ch:      make(chan Event, opts.BufferSize),
+	}
+	sub.blockCond = sync.NewCond(&sub.mu)
+	if opts.Policy == Block {
+		sub.pumpDone = make(chan struct{})
+		go sub.pumpBlock()
+	}
+	b.lock.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.lock.Unlock()
+	return sub
+}
+
+func (b *Bus) unsubscribe(target *Subscription) {
+	b.lock.Lock()
+	found := false
+	for i, sub := range b.subscribers {
+		if sub == target {
+			b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+			found = true
+			break
+		}
+	}
+	b.lock.Unlock()
+	if found {
+		target.closeDown()
+	}
+}
+
+// Publish fans event out to every subscription whose pattern matches,
+// applying each one's own overflow policy. Multiple independent consumers
+// (UI, SQLite logger, metrics exporter) can subscribe without starving
+// each other.
+func (b *Bus) Publish(event Event) {
+	b.lock.Lock()
+	subs := append([]*Subscription(nil), b.subscribers...)
+	b.lock.Unlock()
+
+	for _, sub := range subs {
+		if matchTopic(sub.pattern, event.Type) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// UseTransport wires the given ChatTransport into the Bus: every Event it
+// produces is republished on the Bus for the lifetime of the transport.
+func (b *Bus) UseTransport(t ChatTransport) {
+	b.lock.Lock()
+	b.transport = t
+	b.lock.Unlock()
+
+	go func() {
+		for evt := range t.Events() {
+			b.Publish(evt)
+		}
+	}()
+}
+
+// SendUserMessage hands a user message to the active transport.
+func (b *Bus) SendUserMessage(message string) error {
+	b.lock.Lock()
+	t := b.transport
+	b.lock.Unlock()
+	if t == nil {
+		return errNoTransport
+	}
+	return t.Send(context.Background(), message)
+}
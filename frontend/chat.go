@@ -1,263 +1,549 @@
-package main
-
-import (
-	"bufio"
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"strings"
-	"time"
-
-	"github.com/charmbracelet/bubbles/textinput"
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
-)
-
-// ===============
-// New Event type (matches backend Bus JSON)
-// ===============
-// type Event struct {
-// 	Type      string      `json:"type"`
-// 	Data      interface{} `json:"data"`
-// 	Timestamp int64       `json:"timestamp"`
-// }
-
-// Main application model
-type model struct {
-	textInput       textinput.Model
-	messages        []string
-	streaming       bool
-	currentResponse string
-	width           int
-	height          int
-	err             error
-	program         *tea.Program
-	bus             *Bus
-}
-
-// Styles using Lipgloss
-var (
-	titleStyle = lipgloss.NewStyle().
-		Bold(true).Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).Padding(0, 1)
-
-	userMessageStyle = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#04B575")).
-		Padding(1).MarginBottom(1)
-
-	botMessageStyle = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FF6B6B")).
-		Padding(1).MarginBottom(1)
-
-	streamingMessageStyle = lipgloss.NewStyle().
-		Border(lipgloss.DoubleBorder()).
-		BorderForeground(lipgloss.Color("#FFD23F")).
-		Padding(1).MarginBottom(1)
-
-	inputStyle = lipgloss.NewStyle().
-		Border(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("#874BFD")).
-		Padding(0, 1)
-
-	helpStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).Italic(true)
-)
-
-// Global program reference
-var globalProgram *tea.Program
-
-// Initial model
-func initialModel(bus *Bus) model {
-	ti := textinput.New()
-	ti.Placeholder = "Type your message here..."
-	ti.Focus()
-	ti.CharLimit = 500
-	ti.Width = 50
-
-	return model{
-		textInput: ti,
-		messages:  []string{},
-		streaming: false,
-		bus:       bus,
-	}
-}
-
-// Init: subscribe to bus and forward all events into Bubble Tea
-func (m model) Init() tea.Cmd {
-	ch := m.bus.Subscribe()
-	go func() {
-		for evt := range ch {
-			// Send Event directly into Bubble Tea
-			globalProgram.Send(evt)
-		}
-	}()
-	return tea.Batch(textinput.Blink, tea.EnterAltScreen)
-}
-
-// Update: handle Bubble Tea messages and Events
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-
-	switch v := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.width, m.height = v.Width, v.Height
-		return m, nil
-
-	case tea.KeyMsg:
-		switch v.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
-			return m, tea.Quit
-		case tea.KeyEnter:
-			if !m.streaming && strings.TrimSpace(m.textInput.Value()) != "" {
-				message := strings.TrimSpace(m.textInput.Value())
-				m.messages = append(m.messages, "You: "+message)
-				m.textInput.SetValue("")
-				m.streaming = true
-				return m, sendMessage(message)
-			}
-		}
-
-	case Event: // <-- Our Bus JSON events
-		switch v.Type {
-		case "chat.char":
-			if s, ok := v.Data.(string); ok {
-				m.currentResponse += s
-			}
-		case "chat.complete":
-			if len(m.currentResponse) > 0 {
-				m.messages = append(m.messages, "Bot: "+m.currentResponse)
-			}
-			m.currentResponse = ""
-			m.streaming = false
-		case "tool.start":
-			if data, ok := v.Data.(map[string]interface{}); ok {
-				m.currentResponse += fmt.Sprintf("\n\n🔧 Executing: %s\n", data["command"])
-			}
-		case "tool.output":
-			if s, ok := v.Data.(string); ok {
-				m.currentResponse += fmt.Sprintf("```\n%s\n```\n", s)
-			}
-		case "tool.end":
-			m.currentResponse += "\n"
-		case "stream_err":
-			if s, ok := v.Data.(string); ok {
-				m.messages = append(m.messages, "Error: "+s)
-			}
-			m.currentResponse = ""
-			m.streaming = false
-		}
-	}
-
-	m.textInput, cmd = m.textInput.Update(msg)
-	return m, cmd
-}
-
-// View
-func (m model) View() string {
-	var b strings.Builder
-	b.WriteString(titleStyle.Render("💬 Chat POC - JSON Events") + "\n\n")
-
-	// Show messages
-	b.WriteString(m.renderMessages())
-
-	// Current streaming buffer
-	if m.streaming {
-		streamingText := "Bot: " + m.currentResponse + "▎"
-		streaming := streamingMessageStyle.Width(m.width - 4).Render(streamingText)
-		b.WriteString(streaming + "\n")
-	}
-
-	// Input
-	b.WriteString("\n")
-	input := inputStyle.Width(m.width - 4).Render(m.textInput.View())
-	b.WriteString(input + "\n\n")
-
-	// Help line
-	b.WriteString(helpStyle.Render("Press Enter to send • Ctrl+C to quit"))
-	return b.String()
-}
-
-func (m model) renderMessages() string {
-	var b strings.Builder
-	start := 0
-	maxMessages := 8
-	if len(m.messages) > maxMessages {
-		start = len(m.messages) - maxMessages
-	}
-	for i := start; i < len(m.messages); i++ {
-		msg := m.messages[i]
-		width := m.width - 4
-		if width < 20 {
-			width = 20
-		}
-		if strings.HasPrefix(msg, "You:") {
-			b.WriteString(userMessageStyle.Width(width).Render(msg) + "\n")
-		} else if strings.HasPrefix(msg, "Bot:") {
-			b.WriteString(botMessageStyle.Width(width).Render(msg) + "\n")
-		} else {
-			b.WriteString(msg + "\n")
-		}
-	}
-	return b.String()
-}
-
-// ===============
-// sendMessage now decodes JSON per line (backend sends JSON.stringify(event)+"\n")
-// ===============
-func sendMessage(message string) tea.Cmd {
-	return func() tea.Msg {
-		reqBody := map[string]string{"message": message}
-		jsonBody, err := json.Marshal(reqBody)
-		if err != nil {
-			return Event{Type: "stream_err", Data: "Failed to encode message"}
-		}
-
-		req, err := http.NewRequest("POST", "http://localhost:3000/chat", bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return Event{Type: "stream_err", Data: "Failed to create request"}
-		}
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{Timeout: 60 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			return Event{Type: "stream_err", Data: "Failed to connect to server"}
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			return Event{Type: "stream_err", Data: fmt.Sprintf("Server error: %s", resp.Status)}
-		}
-
-		go func() {
-			defer resp.Body.Close()
-			reader := bufio.NewReader(resp.Body)
-			for {
-				line, err := reader.ReadBytes('\n')
-				if err != nil {
-					if err == io.EOF {
-						appBus.Publish(Event{Type: "stream_end"})
-						return
-					}
-					appBus.Publish(Event{Type: "stream_err", Data: err.Error()})
-					return
-				}
-
-				// decode JSON line
-				var evt Event
-				if err := json.Unmarshal(line, &evt); err != nil {
-					appBus.Publish(Event{Type: "stream_err", Data: "Invalid JSON: " + err.Error()})
-					continue
-				}
-
-				// push into Bus
-				appBus.Publish(evt)
-			}
-		}()
-		return nil
-	}
-}
\ No newline at end of file
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"chat-poc/store"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+)
+
+// ===============
+// New Event type (matches backend Bus JSON)
+// ===============
+// type Event struct {
+// 	Type      string      `json:"type"`
+// 	Data      interface{} `json:"data"`
+// 	Timestamp int64       `json:"timestamp"`
+// }
+
+// Main application model
+type model struct {
+	textInput textinput.Model
+	messages  []string
+	streaming bool
+	width     int
+	height    int
+	err       error
+	program   *tea.Program
+	bus       *Bus
+	busSub    *Subscription
+	connState ConnState
+	dropped   uint64
+
+	db            *store.Store
+	convID        string
+	showHistory   bool
+	historyPane   viewport.Model
+	conversations []store.Conversation
+	selectedConv  int
+
+	currentSegments []respSegment
+	toolCalls       map[string]*ToolCall
+	toolOrder       []string
+	focusedTool     int
+
+	md *markdownRenderer
+}
+
+// Styles using Lipgloss
+var (
+	titleStyle = lipgloss.NewStyle().
+			Bold(true).Foreground(lipgloss.Color("#FAFAFA")).
+			Background(lipgloss.Color("#7D56F4")).Padding(0, 1)
+
+	userMessageStyle = lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("#04B575")).
+				Padding(1).MarginBottom(1)
+
+	botMessageStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#FF6B6B")).
+			Padding(1).MarginBottom(1)
+
+	streamingMessageStyle = lipgloss.NewStyle().
+				Border(lipgloss.DoubleBorder()).
+				BorderForeground(lipgloss.Color("#FFD23F")).
+				Padding(1).MarginBottom(1)
+
+	inputStyle = lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			BorderForeground(lipgloss.Color("#874BFD")).
+			Padding(0, 1)
+
+	helpStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262")).Italic(true)
+
+	historyPaneStyle = lipgloss.NewStyle().
+				Border(lipgloss.NormalBorder()).
+				BorderForeground(lipgloss.Color("#874BFD")).
+				Padding(0, 1).MarginBottom(1)
+)
+
+// Global program reference
+var globalProgram *tea.Program
+
+// historyScrollback is how many past messages initialModel loads from the
+// store on startup.
+const historyScrollback = 20
+
+// Initial model
+func initialModel(bus *Bus, db *store.Store, convID string, theme string) model {
+	ti := textinput.New()
+	ti.Placeholder = "Type your message here..."
+	ti.Focus()
+	ti.CharLimit = 500
+	ti.Width = 50
+
+	m := model{
+		textInput:   ti,
+		messages:    []string{},
+		streaming:   false,
+		bus:         bus,
+		busSub:      bus.Subscribe("*", SubOptions{Policy: Coalesce}),
+		db:          db,
+		convID:      convID,
+		historyPane: viewport.New(0, 0),
+		md:          newMarkdownRenderer(theme),
+	}
+	m.messages = append(m.messages, loadScrollback(db, convID)...)
+	return m
+}
+
+// loadScrollback replays the persisted You:/Bot: lines for a conversation
+// so the TUI doesn't start blank after a restart.
+func loadScrollback(db *store.Store, convID string) []string {
+	if db == nil {
+		return nil
+	}
+	rows, err := db.LastN(convID, historyScrollback)
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	for _, row := range rows {
+		var text string
+		if err := json.Unmarshal([]byte(row.Data), &text); err != nil {
+			continue
+		}
+		switch row.Role {
+		case "user":
+			lines = append(lines, "You: "+text)
+		case "bot":
+			lines = append(lines, "Bot: "+text)
+		}
+	}
+	return lines
+}
+
+// dropPollInterval controls how often the TUI samples its subscription's
+// Dropped() counter for the help line.
+const dropPollInterval = 2 * time.Second
+
+type dropTickMsg struct{ dropped uint64 }
+
+func pollDropped(sub *Subscription) tea.Cmd {
+	return tea.Tick(dropPollInterval, func(time.Time) tea.Msg {
+		return dropTickMsg{dropped: sub.Dropped()}
+	})
+}
+
+// Init: forward every event from the model's Bus subscription into Bubble
+// Tea, and start polling its drop counter. The transport itself is wired up
+// by main() before the program starts.
+func (m model) Init() tea.Cmd {
+	go func() {
+		for evt := range m.busSub.C() {
+			// Send Event directly into Bubble Tea
+			globalProgram.Send(evt)
+		}
+	}()
+	return tea.Batch(textinput.Blink, tea.EnterAltScreen, pollDropped(m.busSub))
+}
+
+// Update: handle Bubble Tea messages and Events
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch v := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = v.Width, v.Height
+		m.historyPane.Width = m.width - 6
+		m.historyPane.Height = 8
+		m.md.Resize(m.width - 4)
+		return m, nil
+
+	case dropTickMsg:
+		m.dropped = v.dropped
+		return m, pollDropped(m.busSub)
+
+	case tea.KeyMsg:
+		switch v.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyEsc:
+			if m.showHistory {
+				m.showHistory = false
+				return m, nil
+			}
+			return m, tea.Quit
+		case tea.KeyCtrlP:
+			m.showHistory = true
+			m.conversations, _ = m.db.Conversations(50)
+			if m.selectedConv > 0 {
+				m.selectedConv--
+			}
+			return m, nil
+		case tea.KeyCtrlN:
+			m.showHistory = true
+			m.conversations, _ = m.db.Conversations(50)
+			if m.selectedConv < len(m.conversations)-1 {
+				m.selectedConv++
+			}
+			return m, nil
+		case tea.KeyTab:
+			if m.streaming && len(m.toolOrder) > 0 {
+				m.focusedTool = (m.focusedTool + 1) % len(m.toolOrder)
+				return m, nil
+			}
+		case tea.KeySpace:
+			if m.streaming && len(m.toolOrder) > 0 {
+				if tc := m.focusedToolCall(); tc != nil {
+					tc.Collapsed = !tc.Collapsed
+				}
+				return m, nil
+			}
+		case tea.KeyCtrlY:
+			if m.streaming && len(m.toolOrder) > 0 {
+				if tc := m.focusedToolCall(); tc != nil {
+					_ = clipboard.WriteAll(tc.Output.String())
+				}
+				return m, nil
+			}
+		case tea.KeyEnter:
+			if m.showHistory {
+				return m.openSelectedConversation(), nil
+			}
+			if !m.streaming && strings.TrimSpace(m.textInput.Value()) != "" {
+				message := strings.TrimSpace(m.textInput.Value())
+				m.textInput.SetValue("")
+				if handled, next := m.handleSlashCommand(message); handled {
+					return next, nil
+				}
+				m.messages = append(m.messages, "You: "+message)
+				m.bus.Publish(Event{Type: "history.user", Data: message})
+				m.streaming = true
+				return m, sendMessage(m.bus, message)
+			}
+		}
+
+	case Event: // <-- Our Bus JSON events
+		switch v.Type {
+		case "sys.conn":
+			if s, ok := v.Data.(string); ok {
+				switch s {
+				case ConnConnected.String():
+					m.connState = ConnConnected
+				case ConnReconnecting.String():
+					m.connState = ConnReconnecting
+				default:
+					m.connState = ConnOffline
+				}
+			}
+		case "chat.char":
+			if s, ok := v.Data.(string); ok {
+				m.appendText(s)
+			}
+		case "chat.complete":
+			if len(m.currentSegments) > 0 {
+				display, plain := m.finalizeSegments()
+				m.messages = append(m.messages, "Bot: "+display)
+				m.bus.Publish(Event{Type: "history.bot", Data: plain})
+			}
+			m.resetTurn()
+		case "tool.start":
+			var data toolStartData
+			if err := decodeEventData(v.Data, &data); err == nil && data.ID != "" {
+				if m.toolCalls == nil {
+					m.toolCalls = map[string]*ToolCall{}
+				}
+				m.toolCalls[data.ID] = &ToolCall{
+					ID:        data.ID,
+					Command:   data.Command,
+					Args:      data.Args,
+					StartedAt: time.Now(),
+				}
+				m.toolOrder = append(m.toolOrder, data.ID)
+				m.currentSegments = append(m.currentSegments, respSegment{kind: "tool", toolID: data.ID})
+				m.focusedTool = len(m.toolOrder) - 1
+			}
+		case "tool.output":
+			var data toolOutputData
+			if err := decodeEventData(v.Data, &data); err == nil {
+				if tc, ok := m.toolCalls[data.ID]; ok {
+					tc.Output.WriteString(data.Output)
+				}
+			}
+		case "tool.end":
+			var data toolEndData
+			if err := decodeEventData(v.Data, &data); err == nil {
+				if tc, ok := m.toolCalls[data.ID]; ok {
+					tc.EndedAt = time.Now()
+					tc.ExitCode = data.ExitCode
+				}
+			}
+		case "stream_err":
+			if s, ok := v.Data.(string); ok {
+				m.messages = append(m.messages, "Error: "+s)
+			}
+			m.resetTurn()
+		}
+	}
+
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// View
+func (m model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("💬 Chat POC - JSON Events") + "\n\n")
+
+	// Show messages
+	b.WriteString(m.renderMessages())
+
+	// Current streaming buffer
+	if m.streaming {
+		b.WriteString(m.renderStreaming() + "\n")
+	}
+
+	// Input
+	b.WriteString("\n")
+	input := inputStyle.Width(m.width - 4).Render(m.textInput.View())
+	b.WriteString(input + "\n\n")
+
+	// Recall pane
+	if m.showHistory {
+		b.WriteString(m.renderHistoryPane() + "\n")
+	}
+
+	// Help line
+	help := fmt.Sprintf(
+		"Press Enter to send • Ctrl+P/Ctrl+N recall conversation • /history /search /new • Ctrl+C to quit • [%s] • dropped %d",
+		m.connState, m.dropped,
+	)
+	b.WriteString(helpStyle.Render(help))
+	return b.String()
+}
+
+func (m model) renderMessages() string {
+	var b strings.Builder
+	start := 0
+	maxMessages := 8
+	if len(m.messages) > maxMessages {
+		start = len(m.messages) - maxMessages
+	}
+	for i := start; i < len(m.messages); i++ {
+		msg := m.messages[i]
+		width := m.width - 4
+		if width < 20 {
+			width = 20
+		}
+		if strings.HasPrefix(msg, "You:") {
+			b.WriteString(userMessageStyle.Width(width).Render(msg) + "\n")
+		} else if strings.HasPrefix(msg, "Bot:") {
+			b.WriteString(botMessageStyle.Width(width).Render(msg) + "\n")
+		} else {
+			b.WriteString(msg + "\n")
+		}
+	}
+	return b.String()
+}
+
+// appendText folds a chat.char chunk onto the last segment if it's already
+// plain text, otherwise starts a new text segment so tool blocks stay
+// interleaved in arrival order.
+func (m *model) appendText(s string) {
+	if n := len(m.currentSegments); n > 0 && m.currentSegments[n-1].kind == "text" {
+		m.currentSegments[n-1].text += s
+		return
+	}
+	m.currentSegments = append(m.currentSegments, respSegment{kind: "text", text: s})
+}
+
+func (m model) focusedToolCall() *ToolCall {
+	if m.focusedTool < 0 || m.focusedTool >= len(m.toolOrder) {
+		return nil
+	}
+	return m.toolCalls[m.toolOrder[m.focusedTool]]
+}
+
+// resetTurn clears per-turn state once a reply has finished or errored.
+func (m *model) resetTurn() {
+	m.currentSegments = nil
+	m.toolCalls = nil
+	m.toolOrder = nil
+	m.focusedTool = 0
+	m.streaming = false
+}
+
+// finalizeSegments renders the completed turn's segments into a display
+// string (with bordered tool blocks, for the message list) and a plain
+// string (for history storage and /search).
+func (m model) finalizeSegments() (display, plain string) {
+	width := m.width - 4
+	if width < 20 {
+		width = 20
+	}
+
+	var d, p strings.Builder
+	for _, seg := range m.currentSegments {
+		switch seg.kind {
+		case "text":
+			d.WriteString(m.md.Render(seg.text))
+			p.WriteString(seg.text)
+		case "tool":
+			tc := m.toolCalls[seg.toolID]
+			if tc == nil {
+				continue
+			}
+			d.WriteString("\n" + tc.render(width, false) + "\n")
+			p.WriteString(fmt.Sprintf("\n[tool %s exit=%d]\n%s\n", tc.Command, tc.ExitCode, tc.Output.String()))
+		}
+	}
+	return d.String(), p.String()
+}
+
+// renderStreaming draws the in-progress turn: plain text segments inline,
+// tool calls as their own bordered blocks, with the focused one highlighted
+// for the Tab/Space/Ctrl+Y keybindings.
+func (m model) renderStreaming() string {
+	width := m.width - 4
+	if width < 20 {
+		width = 20
+	}
+
+	var b strings.Builder
+	b.WriteString("Bot: ")
+	for _, seg := range m.currentSegments {
+		switch seg.kind {
+		case "text":
+			b.WriteString(seg.text)
+		case "tool":
+			tc := m.toolCalls[seg.toolID]
+			if tc == nil {
+				continue
+			}
+			focused := m.focusedToolCall() == tc
+			b.WriteString("\n" + tc.render(width, focused) + "\n")
+		}
+	}
+	b.WriteString("▎")
+	return streamingMessageStyle.Width(width).Render(b.String())
+}
+
+func (m model) renderHistoryPane() string {
+	var b strings.Builder
+	for i, c := range m.conversations {
+		line := fmt.Sprintf("%s  %s", c.ID, c.Title)
+		if i == m.selectedConv {
+			line = "> " + line
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+
+	pane := m.historyPane
+	pane.SetContent(b.String())
+	header := helpStyle.Render("Conversations (Enter to load, Esc to dismiss):") + "\n"
+	return historyPaneStyle.Width(m.width - 4).Render(header + pane.View())
+}
+
+// handleSlashCommand intercepts /history, /search and /new before a message
+// is sent to the transport. It returns handled=false for ordinary chat
+// messages.
+func (m model) handleSlashCommand(input string) (bool, model) {
+	if !strings.HasPrefix(input, "/") {
+		return false, m
+	}
+
+	fields := strings.SplitN(input, " ", 2)
+	switch fields[0] {
+	case "/history":
+		m.showHistory = true
+		m.selectedConv = 0
+		m.conversations, _ = m.db.Conversations(50)
+		return true, m
+
+	case "/search":
+		if len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+			m.messages = append(m.messages, "Error: usage: /search <query>")
+			return true, m
+		}
+		results, err := m.db.Search(strings.TrimSpace(fields[1]), 10)
+		if err != nil {
+			m.messages = append(m.messages, "Error: search failed: "+err.Error())
+			return true, m
+		}
+		if len(results) == 0 {
+			m.messages = append(m.messages, "Found: no matches")
+			return true, m
+		}
+		for _, r := range results {
+			var text string
+			_ = json.Unmarshal([]byte(r.Data), &text)
+			m.messages = append(m.messages, fmt.Sprintf("Found: [%s/%s] %s", r.ConvID, r.Type, text))
+		}
+		return true, m
+
+	case "/new":
+		m.convID = uuid.NewString()
+		m.messages = nil
+		m.bus.Publish(Event{Type: "history.new_conv", Data: m.convID})
+		m.messages = append(m.messages, "Started a new conversation")
+		return true, m
+
+	default:
+		m.messages = append(m.messages, "Error: unknown command "+fields[0])
+		return true, m
+	}
+}
+
+// openSelectedConversation switches the active conversation to whichever
+// entry is highlighted in the recall pane and loads its scrollback.
+func (m model) openSelectedConversation() model {
+	if m.selectedConv < 0 || m.selectedConv >= len(m.conversations) {
+		m.showHistory = false
+		return m
+	}
+	conv := m.conversations[m.selectedConv]
+	m.convID = conv.ID
+	m.messages = loadScrollback(m.db, conv.ID)
+	m.bus.Publish(Event{Type: "history.new_conv", Data: conv.ID})
+	m.showHistory = false
+	return m
+}
+
+// ===============
+// sendMessage hands the message to whichever ChatTransport the Bus has
+// wired up instead of opening its own HTTP request.
+// ===============
+func sendMessage(bus *Bus, message string) tea.Cmd {
+	return func() tea.Msg {
+		if err := bus.SendUserMessage(message); err != nil {
+			return Event{Type: "stream_err", Data: "Failed to send: " + err.Error()}
+		}
+		return nil
+	}
+}
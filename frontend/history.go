@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"chat-poc/store"
+)
+
+// historyLogger persists the Bus event stream to SQLite through a single
+// writer goroutine, so conversations survive restarts without ever
+// blocking the UI loop on a disk write. It subscribes to every event but
+// only writes the ones worth replaying (tool.*, history.*, chat.complete,
+// ...); per-character chat.char frames are skipped without touching the
+// database, since the completed turn's full text already lands via
+// history.bot, so there is no database write per streamed character.
+type historyLogger struct {
+	db     *store.Store
+	convID string
+	seq    int
+}
+
+func newHistoryLogger(db *store.Store, convID string) *historyLogger {
+	h := &historyLogger{db: db}
+	h.setConv(convID)
+	return h
+}
+
+// setConv redirects subsequent writes to a different conversation, used by
+// both the /new slash command and resuming a conversation from the recall
+// pane. It seeds seq from the conversation's existing max seq so resumed
+// writes append after its history instead of colliding with (and
+// overwriting, via INSERT OR REPLACE) its earliest rows. It is only safe
+// to call from the same goroutine that calls run, since seq is not
+// otherwise synchronized.
+func (h *historyLogger) setConv(convID string) {
+	h.convID = convID
+	seq, err := h.db.MaxSeq(convID)
+	if err != nil {
+		seq = 0
+	}
+	h.seq = seq
+}
+
+// run drains events until the channel is closed, appending each one it
+// keeps (everything but history.new_conv and chat.char) to the store keyed
+// by (conv_id, seq) so tool invocations can be replayed verbatim later.
+func (h *historyLogger) run(events <-chan Event) {
+	for evt := range events {
+		if evt.Type == "history.new_conv" {
+			if id, ok := evt.Data.(string); ok {
+				h.setConv(id)
+			}
+			continue
+		}
+		if evt.Type == "chat.char" {
+			continue
+		}
+
+		role := roleFor(evt.Type)
+
+		data, err := json.Marshal(evt.Data)
+		if err != nil {
+			data = []byte("null")
+		}
+
+		h.seq++
+		if err := h.db.Append(store.Message{
+			ConvID: h.convID,
+			Seq:    h.seq,
+			Ts:     time.Now().UnixMilli(),
+			Role:   role,
+			Type:   evt.Type,
+			Data:   string(data),
+		}); err != nil {
+			// Best-effort logging: a failed write shouldn't take down the chat.
+			continue
+		}
+	}
+}
+
+func roleFor(eventType string) string {
+	switch eventType {
+	case "history.user":
+		return "user"
+	case "history.bot":
+		return "bot"
+	default:
+		return "event"
+	}
+}
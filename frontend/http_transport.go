@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpTransport is the original ChatTransport implementation: one HTTP POST
+// per turn, with the response body streamed back as newline-delimited JSON
+// events.
+type httpTransport struct {
+	url    string
+	client *http.Client
+	events chan Event
+}
+
+func newHTTPTransport(url string) *httpTransport {
+	return &httpTransport{
+		url:    url,
+		client: &http.Client{Timeout: 60 * time.Second},
+		events: make(chan Event, 64),
+	}
+}
+
+func (t *httpTransport) Events() <-chan Event { return t.events }
+
+func (t *httpTransport) Close() error { return nil }
+
+func (t *httpTransport) Send(ctx context.Context, message string) error {
+	reqBody := map[string]string{"message": message}
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connect to server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("server error: %s", resp.Status)
+	}
+
+	go t.streamResponse(resp.Body)
+	return nil
+}
+
+// streamResponse decodes one JSON event per line (backend sends
+// JSON.stringify(event)+"\n") and forwards each onto the events channel.
+func (t *httpTransport) streamResponse(body io.ReadCloser) {
+	defer body.Close()
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				t.events <- Event{Type: "stream_end"}
+				return
+			}
+			t.events <- Event{Type: "stream_err", Data: err.Error()}
+			return
+		}
+
+		var evt Event
+		if err := json.Unmarshal(line, &evt); err != nil {
+			t.events <- Event{Type: "stream_err", Data: "Invalid JSON: " + err.Error()}
+			continue
+		}
+		t.events <- evt
+	}
+}
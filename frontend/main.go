@@ -1,24 +1,60 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
+	"chat-poc/store"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
 )
 
 func main() {
+	transportKind := flag.String("transport", string(TransportWS), "chat transport: ws, http, sse, or subprocess")
+	target := flag.String("target", "", "transport target: URL for ws/http/sse, command line for subprocess (defaults to the conventional endpoint for --transport)")
+	dbPath := flag.String("db", "chat-history.db", "path to the SQLite conversation history database")
+	theme := flag.String("theme", "auto", "markdown theme: dark, light, notty, or auto")
+	flag.Parse()
+
 	appBus = NewBus()
 
+	transport, err := NewTransport(TransportKind(*transportKind), *target)
+	if err != nil {
+		fmt.Printf("❌ Invalid transport: %v\n", err)
+		os.Exit(1)
+	}
+	appBus.UseTransport(transport)
+
+	db, err := store.Open(*dbPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to open history database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Resume the most recently active conversation so its scrollback shows
+	// on startup; /new starts a fresh one.
+	convID := uuid.NewString()
+	if convs, err := db.Conversations(1); err == nil && len(convs) > 0 {
+		convID = convs[0].ID
+	}
+	logger := newHistoryLogger(db, convID)
+	// Block rather than drop: a missed write would silently corrupt
+	// conversation recall, which matters far more than this goroutine
+	// lagging a few events behind the UI.
+	go logger.run(appBus.Subscribe("*", SubOptions{Policy: Block}).C())
+
 	// Initialize the TUI program with the initial model
 	// WithAltScreen() creates a full-screen terminal application
-	p := tea.NewProgram(initialModel(appBus), tea.WithAltScreen())
-	
+	p := tea.NewProgram(initialModel(appBus, db, convID, *theme), tea.WithAltScreen())
+
 	// Set global program reference for streaming
 	globalProgram = p
-	
+
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("❌ Error starting chat application: %v\n", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
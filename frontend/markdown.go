@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// defaultMarkdownWidth is used until the first tea.WindowSizeMsg arrives.
+const defaultMarkdownWidth = 76
+
+// markdownRenderer wraps glamour with the width and theme the TUI is
+// currently using. Glamour bakes both into the renderer at construction
+// time, so changing either means rebuilding it.
+type markdownRenderer struct {
+	theme string
+	width int
+	r     *glamour.TermRenderer
+}
+
+// newMarkdownRenderer builds a renderer for theme, one of "dark", "light",
+// "notty" or "auto" (termenv background detection). Anything else falls
+// back to "auto".
+func newMarkdownRenderer(theme string) *markdownRenderer {
+	switch theme {
+	case "dark", "light", "notty", "auto":
+	default:
+		theme = "auto"
+	}
+	mr := &markdownRenderer{theme: theme, width: defaultMarkdownWidth}
+	mr.rebuild()
+	return mr
+}
+
+func (mr *markdownRenderer) rebuild() {
+	opts := []glamour.TermRendererOption{glamour.WithWordWrap(mr.width)}
+	if mr.theme == "auto" {
+		opts = append(opts, glamour.WithAutoStyle())
+	} else {
+		opts = append(opts, glamour.WithStandardStyle(mr.theme))
+	}
+
+	r, err := glamour.NewTermRenderer(opts...)
+	if err != nil {
+		mr.r = nil
+		return
+	}
+	mr.r = r
+}
+
+// Resize rebuilds the renderer if the width changed, so fenced code blocks
+// reflow to the new terminal width on the next render.
+func (mr *markdownRenderer) Resize(width int) {
+	if width <= 0 || width == mr.width {
+		return
+	}
+	mr.width = width
+	mr.rebuild()
+}
+
+// Render converts markdown to ANSI, falling back to the raw text if
+// glamour couldn't be constructed or failed on this input.
+func (mr *markdownRenderer) Render(markdown string) string {
+	if mr.r == nil {
+		return markdown
+	}
+	out, err := mr.r.Render(markdown)
+	if err != nil {
+		return markdown
+	}
+	return strings.TrimRight(out, "\n")
+}
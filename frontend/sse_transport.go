@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sseTransport talks to an OpenAI-compatible streaming endpoint, parsing
+// `data:` frames out of the SSE response and translating them into the same
+// chat.char/chat.complete/tool.* events the other transports emit.
+type sseTransport struct {
+	url    string
+	client *http.Client
+	events chan Event
+}
+
+func newSSETransport(url string) *sseTransport {
+	return &sseTransport{
+		url:    url,
+		client: &http.Client{Timeout: 0}, // streaming response, no overall timeout
+		events: make(chan Event, 64),
+	}
+}
+
+func (t *sseTransport) Events() <-chan Event { return t.events }
+
+func (t *sseTransport) Close() error { return nil }
+
+func (t *sseTransport) Send(ctx context.Context, message string) error {
+	reqBody := map[string]interface{}{
+		"messages": []map[string]string{{"role": "user", "content": message}},
+		"stream":   true,
+	}
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connect to server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("server error: %s", resp.Status)
+	}
+
+	go t.streamResponse(resp.Body)
+	return nil
+}
+
+// sseChunk mirrors the OpenAI chat-completion streaming chunk shape. Index
+// identifies which tool call a delta belongs to, since a single response
+// can interleave several in parallel; Id is only present on the first
+// delta for each one.
+type sseChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+func (t *sseTransport) streamResponse(body io.ReadCloser) {
+	defer body.Close()
+	scanner := bufio.NewScanner(body)
+
+	// open maps an OpenAI tool_calls[].index to the stable ID we assigned
+	// it in tool.start, so tool.output/tool.end can be decoded by toolcall.go
+	// instead of arriving as an ID-less payload that gets silently dropped.
+	open := map[int]string{}
+	closeAll := func() {
+		for _, id := range open {
+			t.events <- Event{Type: "tool.end", Data: toolEndData{ID: id}}
+		}
+		open = map[int]string{}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			closeAll()
+			t.events <- Event{Type: "chat.complete"}
+			return
+		}
+
+		var chunk sseChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.events <- Event{Type: "stream_err", Data: "Invalid SSE frame: " + err.Error()}
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			for _, call := range choice.Delta.ToolCalls {
+				id, seen := open[call.Index]
+				if !seen {
+					id = call.ID
+					if id == "" {
+						id = fmt.Sprintf("tool-%d", call.Index)
+					}
+					open[call.Index] = id
+					t.events <- Event{Type: "tool.start", Data: toolStartData{
+						ID:      id,
+						Command: call.Function.Name,
+					}}
+				}
+				if call.Function.Arguments != "" {
+					t.events <- Event{Type: "tool.output", Data: toolOutputData{
+						ID:     id,
+						Output: call.Function.Arguments,
+					}}
+				}
+			}
+			if choice.Delta.Content != "" {
+				closeAll()
+				t.events <- Event{Type: "chat.char", Data: choice.Delta.Content}
+			}
+			if choice.FinishReason != "" {
+				closeAll()
+				t.events <- Event{Type: "chat.complete"}
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.events <- Event{Type: "stream_err", Data: err.Error()}
+	}
+}
@@ -0,0 +1,182 @@
+// Package store persists chat history to SQLite so the TUI can survive
+// restarts and let users recall or resume past conversations.
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Message is one row of conversation history: either a rendered You:/Bot:
+// line or a raw Bus event, keyed by conversation and an in-conversation
+// sequence number so tool invocations can be replayed verbatim.
+type Message struct {
+	ConvID string
+	Seq    int
+	Ts     int64
+	Role   string
+	Type   string
+	Data   string // JSON-encoded payload
+}
+
+// Conversation is a summary row used to populate the recall list.
+type Conversation struct {
+	ID        string
+	Title     string
+	UpdatedAt int64
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and
+// ensures the schema exists.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("store: open %s: %w", path, err)
+	}
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) migrate() error {
+	_, err := s.db.Exec(`
+CREATE TABLE IF NOT EXISTS messages (
+	conv_id   TEXT NOT NULL,
+	seq       INTEGER NOT NULL,
+	ts        INTEGER NOT NULL,
+	role      TEXT NOT NULL,
+	type      TEXT NOT NULL,
+	data_json TEXT NOT NULL,
+	PRIMARY KEY (conv_id, seq)
+);
+CREATE INDEX IF NOT EXISTS idx_messages_conv ON messages(conv_id);
+CREATE INDEX IF NOT EXISTS idx_messages_role ON messages(role);
+`)
+	if err != nil {
+		return fmt.Errorf("store: migrate: %w", err)
+	}
+	return nil
+}
+
+// Append records one message. It is safe to call from a single dedicated
+// writer goroutine; Store does not serialize concurrent callers itself.
+func (s *Store) Append(m Message) error {
+	_, err := s.db.Exec(
+		`INSERT OR REPLACE INTO messages (conv_id, seq, ts, role, type, data_json) VALUES (?, ?, ?, ?, ?, ?)`,
+		m.ConvID, m.Seq, m.Ts, m.Role, m.Type, m.Data,
+	)
+	if err != nil {
+		return fmt.Errorf("store: append: %w", err)
+	}
+	return nil
+}
+
+// MaxSeq returns the highest seq already stored for convID, or 0 if the
+// conversation has no rows yet, so callers can resume appending after it
+// instead of restarting the sequence from scratch.
+func (s *Store) MaxSeq(convID string) (int, error) {
+	var seq sql.NullInt64
+	err := s.db.QueryRow(`SELECT MAX(seq) FROM messages WHERE conv_id = ?`, convID).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("store: max seq: %w", err)
+	}
+	return int(seq.Int64), nil
+}
+
+// LastN returns the most recent n messages for a conversation, oldest
+// first, suitable for repopulating the TUI on startup or /history recall.
+func (s *Store) LastN(convID string, n int) ([]Message, error) {
+	rows, err := s.db.Query(
+		`SELECT conv_id, seq, ts, role, type, data_json FROM messages
+		 WHERE conv_id = ? ORDER BY seq DESC LIMIT ?`,
+		convID, n,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("store: last n: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ConvID, &m.Seq, &m.Ts, &m.Role, &m.Type, &m.Data); err != nil {
+			return nil, fmt.Errorf("store: scan: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, rows.Err()
+}
+
+// Search looks for query as a substring of data_json across all
+// conversations, most recent first.
+func (s *Store) Search(query string, limit int) ([]Message, error) {
+	rows, err := s.db.Query(
+		`SELECT conv_id, seq, ts, role, type, data_json FROM messages
+		 WHERE data_json LIKE ? ORDER BY ts DESC LIMIT ?`,
+		"%"+query+"%", limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("store: search: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ConvID, &m.Seq, &m.Ts, &m.Role, &m.Type, &m.Data); err != nil {
+			return nil, fmt.Errorf("store: scan: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// Conversations lists the most recently active conversations, newest
+// first, for the recall pane.
+func (s *Store) Conversations(limit int) ([]Conversation, error) {
+	rows, err := s.db.Query(`
+		SELECT conv_id, MAX(ts) AS updated_at,
+		       (SELECT data_json FROM messages m2 WHERE m2.conv_id = m1.conv_id AND m2.role = 'user' ORDER BY m2.seq ASC LIMIT 1)
+		FROM messages m1
+		GROUP BY conv_id
+		ORDER BY updated_at DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var convs []Conversation
+	for rows.Next() {
+		var c Conversation
+		var title sql.NullString
+		if err := rows.Scan(&c.ID, &c.UpdatedAt, &title); err != nil {
+			return nil, fmt.Errorf("store: scan: %w", err)
+		}
+		if title.Valid {
+			var text string
+			if err := json.Unmarshal([]byte(title.String), &text); err == nil {
+				c.Title = text
+			}
+		}
+		convs = append(convs, c)
+	}
+	return convs, rows.Err()
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
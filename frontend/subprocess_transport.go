@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// subprocessTransport runs a local command for each user turn and maps its
+// stdout, line by line, onto the same Event schema the other transports
+// produce. Lines that parse as a JSON Event with a non-empty type are
+// forwarded as-is; anything else is treated as plain chat.char output.
+type subprocessTransport struct {
+	command string
+	args    []string
+	events  chan Event
+}
+
+// newSubprocessTransport takes a command line split on whitespace (shell
+// quoting is not honoured), e.g. "python3 bot.py --model local".
+func newSubprocessTransport(commandLine string) *subprocessTransport {
+	fields := strings.Fields(commandLine)
+	t := &subprocessTransport{events: make(chan Event, 64)}
+	if len(fields) > 0 {
+		t.command = fields[0]
+		t.args = fields[1:]
+	}
+	return t
+}
+
+func (t *subprocessTransport) Events() <-chan Event { return t.events }
+
+func (t *subprocessTransport) Close() error { return nil }
+
+func (t *subprocessTransport) Send(ctx context.Context, message string) error {
+	if t.command == "" {
+		return fmt.Errorf("subprocess transport: no command configured")
+	}
+
+	cmd := exec.CommandContext(ctx, t.command, append(t.args, message)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("open stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start subprocess: %w", err)
+	}
+
+	go t.streamOutput(cmd, stdout)
+	return nil
+}
+
+func (t *subprocessTransport) streamOutput(cmd *exec.Cmd, stdout io.ReadCloser) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		var evt Event
+		if err := json.Unmarshal([]byte(line), &evt); err == nil && evt.Type != "" {
+			t.events <- evt
+			continue
+		}
+		t.events <- Event{Type: "chat.char", Data: line + "\n"}
+	}
+	if err := cmd.Wait(); err != nil {
+		t.events <- Event{Type: "stream_err", Data: err.Error()}
+		return
+	}
+	t.events <- Event{Type: "chat.complete"}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ToolCall tracks one in-flight (or just-finished) tool invocation so it can
+// be rendered as its own collapsible block instead of being flattened into
+// the streaming text buffer.
+type ToolCall struct {
+	ID        string
+	Command   string
+	Args      []string
+	Output    strings.Builder
+	ExitCode  int
+	StartedAt time.Time
+	EndedAt   time.Time
+	Collapsed bool
+}
+
+func (tc *ToolCall) running() bool {
+	return tc.EndedAt.IsZero()
+}
+
+func (tc *ToolCall) duration() time.Duration {
+	if tc.running() {
+		return time.Since(tc.StartedAt)
+	}
+	return tc.EndedAt.Sub(tc.StartedAt)
+}
+
+// toolStartData, toolOutputData and toolEndData mirror the JSON payloads
+// the backend sends on tool.start/tool.output/tool.end, replacing the old
+// map[string]interface{} grab-bag.
+type toolStartData struct {
+	ID      string   `json:"id"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+type toolOutputData struct {
+	ID     string `json:"id"`
+	Output string `json:"output"`
+}
+
+type toolEndData struct {
+	ID       string `json:"id"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// decodeEventData round-trips an Event's loosely-typed Data field through
+// JSON into a concrete struct.
+func decodeEventData(data interface{}, out interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// respSegment is one piece of an in-progress bot turn, in arrival order, so
+// streamed text and tool-call blocks can be interleaved correctly instead of
+// text blindly swallowing tool output as triple-backtick strings.
+type respSegment struct {
+	kind   string // "text" or "tool"
+	text   string
+	toolID string
+}
+
+var (
+	toolBlockStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#3FA7D6")).
+			Padding(0, 1).MarginBottom(1)
+
+	toolBlockFocusedStyle = toolBlockStyle.
+				BorderForeground(lipgloss.Color("#FFD23F"))
+
+	toolHeaderStyle = lipgloss.NewStyle().Bold(true)
+)
+
+// render draws a tool call as its own bordered block: a header line with
+// the command, duration and exit status, and the collected output below it
+// unless the block is collapsed.
+func (tc *ToolCall) render(width int, focused bool) string {
+	status := "running"
+	if !tc.running() {
+		status = fmt.Sprintf("exit %d", tc.ExitCode)
+	}
+	arrow := "▾"
+	if tc.Collapsed {
+		arrow = "▸"
+	}
+	header := toolHeaderStyle.Render(fmt.Sprintf(
+		"%s 🔧 %s  (%s, %s)", arrow, tc.Command, tc.duration().Round(time.Millisecond), status,
+	))
+
+	body := header
+	if !tc.Collapsed {
+		out := strings.TrimRight(tc.Output.String(), "\n")
+		if out != "" {
+			body += "\n" + out
+		}
+	}
+
+	style := toolBlockStyle
+	if focused {
+		style = toolBlockFocusedStyle
+	}
+	return style.Width(width).Render(body)
+}
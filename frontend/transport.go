@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ChatTransport abstracts over how the TUI talks to a chat backend so the
+// Bus can swap between a WebSocket, an HTTP/NDJSON stream, an SSE endpoint
+// or a local subprocess without the rest of the app knowing the difference.
+type ChatTransport interface {
+	// Send delivers a user message to the backend.
+	Send(ctx context.Context, userMsg string) error
+	// Events returns the channel of Events the transport publishes as it
+	// receives them. The ws transport closes it once Close has been
+	// called; the http, sse and subprocess transports never close it.
+	Events() <-chan Event
+	// Close releases any resources (connections, subprocesses) held by the
+	// transport.
+	Close() error
+}
+
+// TransportKind selects which ChatTransport implementation to wire up, set
+// via the --transport CLI flag.
+type TransportKind string
+
+const (
+	TransportWS         TransportKind = "ws"
+	TransportHTTP       TransportKind = "http"
+	TransportSSE        TransportKind = "sse"
+	TransportSubprocess TransportKind = "subprocess"
+)
+
+// defaultTarget returns the conventional endpoint for kind, used when
+// --target is left unset so --transport=http or --transport=sse don't
+// inherit the ws:// default meant for TransportWS.
+func defaultTarget(kind TransportKind) string {
+	switch kind {
+	case TransportHTTP, TransportSSE:
+		return "http://localhost:3000/chat"
+	case TransportSubprocess:
+		return ""
+	default:
+		return "ws://localhost:3000/chat"
+	}
+}
+
+// validateTarget checks target's scheme is one kind can actually dial,
+// so a mismatch fails with a clear message instead of a raw
+// "unsupported protocol scheme" from net/http or nhooyr.io/websocket.
+func validateTarget(kind TransportKind, target string) error {
+	switch kind {
+	case TransportWS, "":
+		if !strings.HasPrefix(target, "ws://") && !strings.HasPrefix(target, "wss://") {
+			return fmt.Errorf("--target must be a ws:// or wss:// URL for the ws transport, got %q", target)
+		}
+	case TransportHTTP, TransportSSE:
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return fmt.Errorf("--target must be an http:// or https:// URL for the %s transport, got %q", kind, target)
+		}
+	}
+	return nil
+}
+
+// NewTransport builds the ChatTransport named by kind. target is the
+// endpoint URL for ws/http/sse transports, or the command line for the
+// subprocess transport; an empty target falls back to defaultTarget(kind).
+func NewTransport(kind TransportKind, target string) (ChatTransport, error) {
+	if target == "" {
+		target = defaultTarget(kind)
+	}
+	if err := validateTarget(kind, target); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case TransportHTTP:
+		return newHTTPTransport(target), nil
+	case TransportSSE:
+		return newSSETransport(target), nil
+	case TransportSubprocess:
+		return newSubprocessTransport(target), nil
+	case TransportWS, "":
+		return newWSClient(target), nil
+	default:
+		return nil, errUnknownTransport(kind)
+	}
+}
+
+type errUnknownTransport TransportKind
+
+func (e errUnknownTransport) Error() string {
+	return "unknown transport kind: " + string(e)
+}
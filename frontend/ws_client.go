@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+var errWSNotConnected = errors.New("websocket: not connected")
+
+// wsClient is the ChatTransport implementation that maintains a single
+// persistent WebSocket connection to the chat backend, reconnecting with
+// exponential backoff on failure and resuming the stream from the last
+// event it saw.
+type wsClient struct {
+	url    string
+	events chan Event
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock   sync.Mutex
+	conn   *websocket.Conn
+	lastID string
+}
+
+func newWSClient(url string) *wsClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &wsClient{
+		url:    url,
+		events: make(chan Event, 64),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go c.run()
+	return c
+}
+
+func (c *wsClient) Events() <-chan Event { return c.events }
+
+func (c *wsClient) Close() error {
+	c.cancel()
+	c.lock.Lock()
+	conn := c.conn
+	c.lock.Unlock()
+	if conn != nil {
+		return conn.Close(websocket.StatusNormalClosure, "closing")
+	}
+	return nil
+}
+
+// run dials the backend and keeps it connected until the client is closed,
+// backing off between attempts and publishing sys.conn status events so the
+// TUI can render a connection indicator.
+func (c *wsClient) run() {
+	backoff := time.Second
+	const maxBackoff = 30 * time.Second
+
+	for c.ctx.Err() == nil {
+		c.publish(Event{Type: "sys.conn", Data: ConnReconnecting.String()})
+
+		conn, _, err := websocket.Dial(c.ctx, c.url, nil)
+		if err != nil {
+			c.publish(Event{Type: "sys.conn", Data: ConnOffline.String()})
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))):
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+
+		c.lock.Lock()
+		c.conn = conn
+		c.lock.Unlock()
+		backoff = time.Second
+		c.publish(Event{Type: "sys.conn", Data: ConnConnected.String()})
+
+		c.resume()
+		c.readLoop(conn)
+
+		c.lock.Lock()
+		c.conn = nil
+		c.lock.Unlock()
+	}
+	close(c.events)
+}
+
+func (c *wsClient) publish(evt Event) {
+	select {
+	case c.events <- evt:
+	case <-c.ctx.Done():
+	}
+}
+
+// resume tells the backend where we left off so it can replay any chat.char
+// frames we missed while disconnected.
+func (c *wsClient) resume() {
+	if c.lastID == "" {
+		return
+	}
+	c.writeEvent(Event{Type: "stream.resume", Data: c.lastID})
+}
+
+func (c *wsClient) readLoop(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.Read(c.ctx)
+		if err != nil {
+			return
+		}
+
+		var evt Event
+		if err := json.Unmarshal(data, &evt); err != nil {
+			c.publish(Event{Type: "stream_err", Data: "Invalid JSON: " + err.Error()})
+			continue
+		}
+		if evt.ID != "" {
+			c.lastID = evt.ID
+		}
+		c.publish(evt)
+	}
+}
+
+func (c *wsClient) writeEvent(evt Event) error {
+	c.lock.Lock()
+	conn := c.conn
+	c.lock.Unlock()
+	if conn == nil {
+		return errWSNotConnected
+	}
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return conn.Write(c.ctx, websocket.MessageText, data)
+}
+
+// Send writes a user message directly on the active WebSocket connection.
+// Messages are not queued: while no connection is up it returns
+// errWSNotConnected and the message is not sent later.
+func (c *wsClient) Send(ctx context.Context, message string) error {
+	return c.writeEvent(Event{Type: "chat.send", Data: message})
+}